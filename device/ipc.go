@@ -0,0 +1,39 @@
+/* SPDX-License-Identifier: MIT
+ *
+ * Copyright (C) 2017-2023 WireGuard LLC. All Rights Reserved.
+ */
+
+package device
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IpcGetPoolStats renders Device.PoolStats as sorted uapi key=value lines for the get_pool_stats=1 command.
+func (device *Device) IpcGetPoolStats() string {
+	return formatPoolStats(device.PoolStats())
+}
+
+// formatPoolStats is the testable core of IpcGetPoolStats: it doesn't take a
+// *Device so it can be exercised without one.
+func formatPoolStats(stats map[string]PoolStats) string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		s := stats[name]
+		fmt.Fprintf(&sb, "pool_%s_gets=%d\n", name, s.Gets)
+		fmt.Fprintf(&sb, "pool_%s_puts=%d\n", name, s.Puts)
+		fmt.Fprintf(&sb, "pool_%s_in_use=%d\n", name, s.InUse)
+		fmt.Fprintf(&sb, "pool_%s_high_water=%d\n", name, s.HighWater)
+		fmt.Fprintf(&sb, "pool_%s_wait_count=%d\n", name, s.WaitCount)
+		fmt.Fprintf(&sb, "pool_%s_wait_nanos=%d\n", name, s.WaitTime.Nanoseconds())
+	}
+	return sb.String()
+}
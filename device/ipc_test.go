@@ -0,0 +1,45 @@
+/* SPDX-License-Identifier: MIT
+ *
+ * Copyright (C) 2017-2023 WireGuard LLC. All Rights Reserved.
+ */
+
+package device
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatPoolStats(t *testing.T) {
+	stats := map[string]PoolStats{
+		"message_buffers":  {Gets: 5, Puts: 4, InUse: 1, HighWater: 2, WaitCount: 1, WaitTime: 3 * time.Millisecond},
+		"inbound_elements": {Gets: 1, Puts: 1},
+	}
+
+	out := formatPoolStats(stats)
+
+	// Pool names must appear in sorted order, each followed by its own
+	// pool_<name>_* keys.
+	firstInbound := strings.Index(out, "pool_inbound_elements_")
+	firstMessage := strings.Index(out, "pool_message_buffers_")
+	if firstInbound == -1 || firstMessage == -1 || firstInbound > firstMessage {
+		t.Fatalf("expected pool_inbound_elements_* before pool_message_buffers_*, got:\n%s", out)
+	}
+
+	want := []string{
+		"pool_message_buffers_gets=5\n",
+		"pool_message_buffers_puts=4\n",
+		"pool_message_buffers_in_use=1\n",
+		"pool_message_buffers_high_water=2\n",
+		"pool_message_buffers_wait_count=1\n",
+		"pool_message_buffers_wait_nanos=3000000\n",
+		"pool_inbound_elements_gets=1\n",
+		"pool_inbound_elements_puts=1\n",
+	}
+	for _, line := range want {
+		if !strings.Contains(out, line) {
+			t.Fatalf("expected %q in output, got:\n%s", line, out)
+		}
+	}
+}
@@ -6,95 +6,128 @@
 package device
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// WaitPool is a per-Device bounded object pool; Get blocks until Put frees a slot once max items are checked out.
 type WaitPool struct {
-	pool  []any
-	cond  sync.Cond
-	lock  sync.Mutex
-	count atomic.Uint32
-	max   uint32
-}
-
-var (
-	inboundElementsContainer  *WaitPool
-	outboundElementsContainer *WaitPool
-	messageBuffers            *WaitPool
-	inboundElements           *WaitPool
-	outboundElements          *WaitPool
-)
+	pool sync.Pool
+	sem  chan struct{}
+
+	gets      atomic.Uint64
+	puts      atomic.Uint64
+	inUse     atomic.Uint32
+	highWater atomic.Uint32
+	waitCount atomic.Uint64
+	waitNanos atomic.Int64
+}
+
+// PoolStats is a point-in-time snapshot of a WaitPool's usage counters.
+type PoolStats struct {
+	Gets      uint64
+	Puts      uint64
+	InUse     uint32
+	HighWater uint32
+	WaitCount uint64
+	WaitTime  time.Duration
+}
 
 func NewWaitPool(max uint32, new func() any) *WaitPool {
-	pool := make([]any, max)
-	var i uint32
-	for i = 0; i < max; i++ {
-		pool[i] = new()
+	p := &WaitPool{
+		pool: sync.Pool{New: new},
+		sem:  make(chan struct{}, max),
+	}
+	for i := uint32(0); i < max; i++ {
+		p.sem <- struct{}{}
 	}
-	p := &WaitPool{pool: pool, max: max}
-	p.cond = sync.Cond{L: &p.lock}
 	return p
 }
 
 func (p *WaitPool) Get() any {
-
-	for p.count.Load() >= p.max {
-		p.cond.Wait()
+	select {
+	case <-p.sem:
+	default:
+		start := time.Now()
+		<-p.sem
+		p.waitCount.Add(1)
+		p.waitNanos.Add(int64(time.Since(start)))
 	}
-	p.lock.Lock()
-	defer p.lock.Unlock()
-	item := p.pool[p.count.Load()]
-	p.count.Add(1)
-	return item
-}
-
-func (p *WaitPool) Put(x any) {
-	p.lock.Lock()
-	p.count.Add(^uint32(0))
-	p.pool[p.count.Load()] = x
-	p.lock.Unlock()
-
-	p.cond.Signal()
+	return p.acquired()
 }
 
-func (device *Device) PopulatePools() {
-	if inboundElementsContainer == nil {
-		inboundElementsContainer = NewWaitPool(PreallocatedBuffersPerPool, func() any {
-			s := make([]*QueueInboundElement, 0, device.BatchSize())
-			return &QueueInboundElementsContainer{elems: s}
-		})
+// GetContext behaves like Get, but returns ctx.Err() if ctx is done before a slot becomes available.
+func (p *WaitPool) GetContext(ctx context.Context) (any, error) {
+	select {
+	case <-p.sem:
+		return p.acquired(), nil
+	default:
 	}
-	device.pool.inboundElementsContainer = inboundElementsContainer
 
-	if outboundElementsContainer == nil {
-		outboundElementsContainer = NewWaitPool(PreallocatedBuffersPerPool, func() any {
-			s := make([]*QueueOutboundElement, 0, device.BatchSize())
-			return &QueueOutboundElementsContainer{elems: s}
-		})
+	start := time.Now()
+	select {
+	case <-p.sem:
+		p.waitCount.Add(1)
+		p.waitNanos.Add(int64(time.Since(start)))
+		return p.acquired(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	device.pool.outboundElementsContainer = outboundElementsContainer
+}
 
-	if messageBuffers == nil {
-		messageBuffers = NewWaitPool(PreallocatedBuffersPerPool, func() any {
-			return new([MaxMessageSize]byte)
-		})
+// acquired records bookkeeping for a slot that has already been taken from
+// sem, and returns the item to hand back to the caller.
+func (p *WaitPool) acquired() any {
+	p.gets.Add(1)
+	inUse := p.inUse.Add(1)
+	for {
+		high := p.highWater.Load()
+		if inUse <= high || p.highWater.CompareAndSwap(high, inUse) {
+			break
+		}
 	}
-	device.pool.messageBuffers = messageBuffers
+	return p.pool.Get()
+}
 
-	if inboundElements == nil {
-		inboundElements = NewWaitPool(PreallocatedBuffersPerPool, func() any {
-			return new(QueueInboundElement)
-		})
-	}
-	device.pool.inboundElements = inboundElements
+func (p *WaitPool) Put(x any) {
+	p.pool.Put(x)
+	p.puts.Add(1)
+	p.inUse.Add(^uint32(0))
+	p.sem <- struct{}{}
+}
 
-	if outboundElements == nil {
-		outboundElements = NewWaitPool(PreallocatedBuffersPerPool, func() any {
-			return new(QueueOutboundElement)
-		})
+// Stats returns a snapshot of this pool's usage counters.
+func (p *WaitPool) Stats() PoolStats {
+	return PoolStats{
+		Gets:      p.gets.Load(),
+		Puts:      p.puts.Load(),
+		InUse:     p.inUse.Load(),
+		HighWater: p.highWater.Load(),
+		WaitCount: p.waitCount.Load(),
+		WaitTime:  time.Duration(p.waitNanos.Load()),
 	}
-	device.pool.outboundElements = outboundElements
+}
+
+func (device *Device) PopulatePools() {
+	device.pool.inboundElementsContainer = NewWaitPool(PreallocatedBuffersPerPool, func() any {
+		s := make([]*QueueInboundElement, 0, device.BatchSize())
+		return &QueueInboundElementsContainer{elems: s}
+	})
+	device.pool.outboundElementsContainer = NewWaitPool(PreallocatedBuffersPerPool, func() any {
+		s := make([]*QueueOutboundElement, 0, device.BatchSize())
+		return &QueueOutboundElementsContainer{elems: s}
+	})
+	device.pool.messageBuffers = NewWaitPool(PreallocatedBuffersPerPool, func() any {
+		return new([MaxMessageSize]byte)
+	})
+	device.pool.inboundElements = NewWaitPool(PreallocatedBuffersPerPool, func() any {
+		return new(QueueInboundElement)
+	})
+	device.pool.outboundElements = NewWaitPool(PreallocatedBuffersPerPool, func() any {
+		return new(QueueOutboundElement)
+	})
 }
 
 func (device *Device) GetInboundElementsContainer() *QueueInboundElementsContainer {
@@ -103,6 +136,16 @@ func (device *Device) GetInboundElementsContainer() *QueueInboundElementsContain
 	return c
 }
 
+func (device *Device) GetInboundElementsContainerContext(ctx context.Context) (*QueueInboundElementsContainer, error) {
+	item, err := device.pool.inboundElementsContainer.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := item.(*QueueInboundElementsContainer)
+	c.Mutex = sync.Mutex{}
+	return c, nil
+}
+
 func (device *Device) PutInboundElementsContainer(c *QueueInboundElementsContainer) {
 	for i := range c.elems {
 		c.elems[i] = nil
@@ -117,6 +160,16 @@ func (device *Device) GetOutboundElementsContainer() *QueueOutboundElementsConta
 	return c
 }
 
+func (device *Device) GetOutboundElementsContainerContext(ctx context.Context) (*QueueOutboundElementsContainer, error) {
+	item, err := device.pool.outboundElementsContainer.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := item.(*QueueOutboundElementsContainer)
+	c.Mutex = sync.Mutex{}
+	return c, nil
+}
+
 func (device *Device) PutOutboundElementsContainer(c *QueueOutboundElementsContainer) {
 	for i := range c.elems {
 		c.elems[i] = nil
@@ -129,6 +182,14 @@ func (device *Device) GetMessageBuffer() *[MaxMessageSize]byte {
 	return device.pool.messageBuffers.Get().(*[MaxMessageSize]byte)
 }
 
+func (device *Device) GetMessageBufferContext(ctx context.Context) (*[MaxMessageSize]byte, error) {
+	item, err := device.pool.messageBuffers.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return item.(*[MaxMessageSize]byte), nil
+}
+
 func (device *Device) PutMessageBuffer(msg *[MaxMessageSize]byte) {
 	device.pool.messageBuffers.Put(msg)
 }
@@ -137,6 +198,14 @@ func (device *Device) GetInboundElement() *QueueInboundElement {
 	return device.pool.inboundElements.Get().(*QueueInboundElement)
 }
 
+func (device *Device) GetInboundElementContext(ctx context.Context) (*QueueInboundElement, error) {
+	item, err := device.pool.inboundElements.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return item.(*QueueInboundElement), nil
+}
+
 func (device *Device) PutInboundElement(elem *QueueInboundElement) {
 	elem.clearPointers()
 	device.pool.inboundElements.Put(elem)
@@ -146,7 +215,26 @@ func (device *Device) GetOutboundElement() *QueueOutboundElement {
 	return device.pool.outboundElements.Get().(*QueueOutboundElement)
 }
 
+func (device *Device) GetOutboundElementContext(ctx context.Context) (*QueueOutboundElement, error) {
+	item, err := device.pool.outboundElements.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return item.(*QueueOutboundElement), nil
+}
+
 func (device *Device) PutOutboundElement(elem *QueueOutboundElement) {
 	elem.clearPointers()
 	device.pool.outboundElements.Put(elem)
 }
+
+// PoolStats reports usage counters for every pool owned by this Device, keyed by pool name.
+func (device *Device) PoolStats() map[string]PoolStats {
+	return map[string]PoolStats{
+		"message_buffers":             device.pool.messageBuffers.Stats(),
+		"inbound_elements":            device.pool.inboundElements.Stats(),
+		"outbound_elements":           device.pool.outboundElements.Stats(),
+		"inbound_elements_container":  device.pool.inboundElementsContainer.Stats(),
+		"outbound_elements_container": device.pool.outboundElementsContainer.Stats(),
+	}
+}
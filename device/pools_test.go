@@ -0,0 +1,93 @@
+/* SPDX-License-Identifier: MIT
+ *
+ * Copyright (C) 2017-2023 WireGuard LLC. All Rights Reserved.
+ */
+
+package device
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWaitPoolConcurrent hammers Get/Put from many goroutines and checks
+// that the number of items in use never exceeds max, guarding against the
+// old count-read-outside-the-lock race handing out the same slot twice.
+func TestWaitPoolConcurrent(t *testing.T) {
+	const max = 8
+	const goroutines = 32
+	const iterations = 1000
+
+	p := NewWaitPool(max, func() any { return new(int) })
+
+	var inUse atomic.Int32
+	var observedMax atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				item := p.Get()
+				n := inUse.Add(1)
+				for {
+					m := observedMax.Load()
+					if n <= m || observedMax.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				inUse.Add(-1)
+				p.Put(item)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if observedMax.Load() > max {
+		t.Fatalf("observed %d items in use concurrently, want <= %d", observedMax.Load(), max)
+	}
+}
+
+// TestWaitPoolGetContextCancel exhausts a pool and checks that GetContext
+// returns ctx.Err() promptly once its context is canceled, instead of
+// parking forever like Get would.
+func TestWaitPoolGetContextCancel(t *testing.T) {
+	p := NewWaitPool(1, func() any { return new(int) })
+	p.Get() // exhaust the only slot
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.GetContext(ctx)
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond) // give the goroutine a chance to block on GetContext
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("GetContext returned %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetContext did not return after its context was canceled")
+	}
+}
+
+// TestWaitPoolGetContextSucceeds checks that GetContext returns an item
+// without error when a slot is available.
+func TestWaitPoolGetContextSucceeds(t *testing.T) {
+	p := NewWaitPool(1, func() any { return new(int) })
+
+	item, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext returned unexpected error: %v", err)
+	}
+	if item == nil {
+		t.Fatal("GetContext returned a nil item")
+	}
+}